@@ -0,0 +1,115 @@
+package main
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"sync"
+)
+
+// bloomFilter is a fixed-size probabilistic set, sized up front for a target
+// capacity and false-positive rate. It trades a small, known false-positive
+// rate for memory bounded independently of how many keys are inserted,
+// which is what makes probabilistic mode viable for very large streams.
+// It is safe for concurrent use.
+type bloomFilter struct {
+	mu       sync.RWMutex
+	bits     []uint64
+	numBits  uint64
+	numHash  int
+	inserted int
+	capacity int
+}
+
+// newBloomFilter sizes a bloomFilter for capacity items at the target false
+// positive rate fpr, using the standard optimal-bits/optimal-hashes formulas.
+func newBloomFilter(capacity int, fpr float64) *bloomFilter {
+	if capacity < 1 {
+		capacity = 1
+	}
+	if fpr <= 0 || fpr >= 1 {
+		fpr = 0.01
+	}
+
+	numBits := uint64(math.Ceil(-float64(capacity) * math.Log(fpr) / (math.Ln2 * math.Ln2)))
+	if numBits < 64 {
+		numBits = 64
+	}
+	numHash := int(math.Round(float64(numBits) / float64(capacity) * math.Ln2))
+	if numHash < 1 {
+		numHash = 1
+	}
+
+	return &bloomFilter{
+		bits:     make([]uint64, (numBits+63)/64),
+		numBits:  numBits,
+		numHash:  numHash,
+		capacity: capacity,
+	}
+}
+
+// hashes returns two independent 64-bit hashes of key, combined via
+// Kirsch-Mitzenmacher double hashing to derive numHash bit positions
+// without running numHash independent hash functions.
+func (f *bloomFilter) hashes(key string) (uint64, uint64) {
+	h1 := fnv.New64a()
+	h1.Write([]byte(key))
+	sum1 := h1.Sum64()
+
+	h2 := fnv.New64()
+	h2.Write([]byte(key))
+	sum2 := h2.Sum64()
+
+	return sum1, sum2
+}
+
+// Add inserts key into the filter.
+func (f *bloomFilter) Add(key string) {
+	h1, h2 := f.hashes(key)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := 0; i < f.numHash; i++ {
+		idx := (h1 + uint64(i)*h2) % f.numBits
+		f.bits[idx/64] |= 1 << (idx % 64)
+	}
+	f.inserted++
+}
+
+// Test reports whether key may have been inserted. False positives are
+// possible; false negatives are not.
+func (f *bloomFilter) Test(key string) bool {
+	h1, h2 := f.hashes(key)
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for i := 0; i < f.numHash; i++ {
+		idx := (h1 + uint64(i)*h2) % f.numBits
+		if f.bits[idx/64]&(1<<(idx%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadFactor returns the fraction of the configured capacity inserted so far.
+func (f *bloomFilter) LoadFactor() float64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return float64(f.inserted) / float64(f.capacity)
+}
+
+// EstimatedFPR estimates the filter's current false-positive rate from the
+// fraction of bits set, following the standard Bloom filter approximation
+// (1 - e^(-kn/m))^k.
+func (f *bloomFilter) EstimatedFPR() float64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var onBits uint64
+	for _, w := range f.bits {
+		onBits += uint64(bits.OnesCount64(w))
+	}
+	ratio := float64(onBits) / float64(f.numBits)
+	return math.Pow(ratio, float64(f.numHash))
+}
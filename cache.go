@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Cache abstracts the storage backend used by Deduplicator to track the
+// last-seen time for a dedup key. Implementations must be safe for
+// concurrent use.
+type Cache interface {
+	// Get returns the last-seen time for key and whether it was found.
+	Get(key string) (time.Time, bool)
+
+	// Set records key as seen at time t.
+	Set(key string, t time.Time)
+
+	// Delete removes key from the cache.
+	Delete(key string)
+
+	// Len returns the number of entries currently tracked, or -1 if the
+	// backend cannot report it (Memcached).
+	Len() int
+
+	// CheckAndSet atomically checks whether key has already been recorded
+	// as seen within period (a zero period meaning permanently) and, if
+	// not, records it as seen now. It reports whether key was already a
+	// duplicate. The check and the record must happen as a single
+	// critical section so concurrent callers for the same key can't all
+	// observe "not seen" - see rotatingFilter.Seen for the equivalent
+	// probabilistic-mode guarantee.
+	CheckAndSet(key string, period time.Duration) bool
+}
+
+// expirer is implemented by Cache backends that need the Deduplicator to
+// actively sweep expired entries out of the backend. Backends with native
+// TTL support (Redis, Memcached) don't implement it, since expiry there is
+// handled server-side and cleanupExpired becomes a no-op for them.
+type expirer interface {
+	expire(period time.Duration)
+}
+
+// memoryCache is the default in-process Cache backend. It reproduces the
+// original single-process map behavior of Deduplicator.
+type memoryCache struct {
+	mu   sync.RWMutex
+	seen map[string]time.Time
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{seen: make(map[string]time.Time)}
+}
+
+func (c *memoryCache) Get(key string) (time.Time, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	t, found := c.seen[key]
+	return t, found
+}
+
+func (c *memoryCache) Set(key string, t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen[key] = t
+}
+
+func (c *memoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.seen, key)
+}
+
+func (c *memoryCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.seen)
+}
+
+func (c *memoryCache) CheckAndSet(key string, period time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.checkAndSetLocked(key, period, time.Now())
+}
+
+// checkAndSetLocked implements CheckAndSet's check-then-record logic under
+// c.mu already held, so persistentCache can extend the same critical
+// section to cover its WAL append.
+func (c *memoryCache) checkAndSetLocked(key string, period time.Duration, now time.Time) bool {
+	if lastSeen, found := c.seen[key]; found {
+		if period == 0 || now.Sub(lastSeen) < period {
+			return true
+		}
+	}
+	c.seen[key] = now
+	return false
+}
+
+// expire removes entries whose last-seen time is older than period. It is
+// called periodically by Deduplicator.startCleanup to keep the map from
+// growing indefinitely in timed deduplication mode.
+func (c *memoryCache) expire(period time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for key, lastSeen := range c.seen {
+		if now.Sub(lastSeen) > period {
+			delete(c.seen, key)
+		}
+	}
+}
+
+// newCache constructs the Cache backend selected by the -cache flag.
+// connURL is ignored for the "memory" backend and required for the others.
+// If stateDir is non-empty, the "memory" backend is made durable across
+// restarts via newPersistentCache; it is ignored for the remote backends,
+// which are already durable server-side.
+func newCache(kind, connURL, stateDir string, period time.Duration) (Cache, error) {
+	switch kind {
+	case "", "memory":
+		if stateDir != "" {
+			return newPersistentCache(stateDir, period)
+		}
+		return newMemoryCache(), nil
+	case "redis":
+		return newRedisCache(connURL)
+	case "memcached":
+		return newMemcachedCache(connURL)
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q (want memory, redis, or memcached)", kind)
+	}
+}
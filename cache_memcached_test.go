@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMemcachedServer is a minimal in-process stand-in for a Memcached
+// server, speaking just enough of the text protocol (set/add/gets/delete)
+// for memcachedCache's tests - the same role miniredis plays for the Redis
+// backend, without pulling in an external dependency.
+type fakeMemcachedServer struct {
+	mu    sync.Mutex
+	items map[string][]byte
+}
+
+func newFakeMemcachedServer(t *testing.T) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	t.Cleanup(func() { lis.Close() })
+
+	srv := &fakeMemcachedServer{items: make(map[string][]byte)}
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go srv.handle(conn)
+		}
+	}()
+
+	return lis.Addr().String()
+}
+
+func (s *fakeMemcachedServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return
+		}
+
+		switch fields[0] {
+		case "set", "add":
+			key := fields[1]
+			size, _ := strconv.Atoi(fields[4])
+			data := make([]byte, size+2) // payload plus trailing \r\n
+			if _, err := io.ReadFull(r, data); err != nil {
+				return
+			}
+			value := append([]byte(nil), data[:size]...)
+
+			s.mu.Lock()
+			_, exists := s.items[key]
+			if fields[0] == "add" && exists {
+				s.mu.Unlock()
+				w.WriteString("NOT_STORED\r\n")
+			} else {
+				s.items[key] = value
+				s.mu.Unlock()
+				w.WriteString("STORED\r\n")
+			}
+			w.Flush()
+
+		case "get", "gets":
+			key := fields[1]
+			s.mu.Lock()
+			value, ok := s.items[key]
+			s.mu.Unlock()
+			if ok {
+				fmt.Fprintf(w, "VALUE %s 0 %d\r\n", key, len(value))
+				w.Write(value)
+				w.WriteString("\r\n")
+			}
+			w.WriteString("END\r\n")
+			w.Flush()
+
+		case "delete":
+			key := fields[1]
+			s.mu.Lock()
+			_, ok := s.items[key]
+			delete(s.items, key)
+			s.mu.Unlock()
+			if ok {
+				w.WriteString("DELETED\r\n")
+			} else {
+				w.WriteString("NOT_FOUND\r\n")
+			}
+			w.Flush()
+
+		default:
+			w.WriteString("ERROR\r\n")
+			w.Flush()
+		}
+	}
+}
+
+func newTestMemcachedCache(t *testing.T) *memcachedCache {
+	t.Helper()
+	addr := newFakeMemcachedServer(t)
+	c, err := newMemcachedCache(addr)
+	if err != nil {
+		t.Fatalf("newMemcachedCache: %v", err)
+	}
+	return c
+}
+
+func TestMemcachedCacheGetSetDelete(t *testing.T) {
+	c := newTestMemcachedCache(t)
+	sum := sha256.Sum256([]byte("world"))
+	key := string(sum[:])
+
+	if _, found := c.Get(key); found {
+		t.Fatalf("Get on empty cache: got found=true, want false")
+	}
+
+	now := time.Now()
+	c.Set(key, now)
+	if got, found := c.Get(key); !found || got.UnixNano() != now.UnixNano() {
+		t.Fatalf("Get(sha256 key) = %v, %v; want %v, true", got, found, now)
+	}
+
+	c.Delete(key)
+	if _, found := c.Get(key); found {
+		t.Fatalf("Get after Delete: got found=true, want false")
+	}
+}
+
+// TestMemcachedCacheKeyEncoding exercises CheckAndSet with a real
+// sha256-shaped key (raw 32-byte digest output), as deriveKey produces and
+// main.go passes straight into Cache.CheckAndSet. Most such keys contain
+// bytes Memcached's text protocol rejects as key characters; if
+// memcachedCache ever stops encoding the key before it reaches the client,
+// every Add call fails with ErrMalformedKey and CheckAndSet's error branch
+// reports "not a duplicate" for every call, silently disabling
+// deduplication entirely.
+func TestMemcachedCacheKeyEncoding(t *testing.T) {
+	c := newTestMemcachedCache(t)
+
+	sum := sha256.Sum256([]byte("hello"))
+	key := string(sum[:])
+
+	if dup := c.CheckAndSet(key, 0); dup {
+		t.Fatalf("first CheckAndSet on a sha256-shaped key reported a duplicate")
+	}
+	if dup := c.CheckAndSet(key, 0); !dup {
+		t.Fatalf("second CheckAndSet on the same sha256-shaped key did not report a duplicate")
+	}
+}
+
+func TestTTLSeconds(t *testing.T) {
+	cases := []struct {
+		period time.Duration
+		want   int32
+	}{
+		{0, 0},
+		{-time.Second, 0},
+		{500 * time.Millisecond, 1},
+		{time.Second, 1},
+		{90 * time.Second, 90},
+	}
+	for _, tc := range cases {
+		if got := ttlSeconds(tc.period); got != tc.want {
+			t.Errorf("ttlSeconds(%v) = %d, want %d", tc.period, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	redis "github.com/go-redis/redis"
+)
+
+// redisCache is a Cache backend that shares dedup state across a fleet of
+// instances via a Redis server. TTLs are delegated natively to Redis, so
+// Deduplicator.cleanupExpired is a no-op for this backend: expired keys
+// simply disappear server-side and redisCache does not implement expirer.
+type redisCache struct {
+	client *redis.Client
+	period time.Duration
+}
+
+func newRedisCache(connURL string) (*redisCache, error) {
+	opts, err := redis.ParseURL(connURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing redis connection URL: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping().Err(); err != nil {
+		return nil, fmt.Errorf("connecting to redis: %w", err)
+	}
+
+	return &redisCache{client: client}, nil
+}
+
+func (c *redisCache) Get(key string) (time.Time, bool) {
+	val, err := c.client.Get(key).Int64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, val), true
+}
+
+func (c *redisCache) Set(key string, t time.Time) {
+	// period is attached by the Deduplicator via SetPeriod before first use;
+	// a zero period means permanent deduplication, so no expiry is set.
+	c.client.Set(key, t.UnixNano(), c.period)
+}
+
+func (c *redisCache) Delete(key string) {
+	c.client.Del(key)
+}
+
+func (c *redisCache) Len() int {
+	n, err := c.client.DBSize().Result()
+	if err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+// CheckAndSet atomically checks whether key has already been recorded and,
+// if not, records it as seen now, via Redis's SETNX so the check-and-record
+// happens as a single server-side operation: concurrent callers for the
+// same key can't both observe "not seen".
+func (c *redisCache) CheckAndSet(key string, period time.Duration) bool {
+	wasSet, err := c.client.SetNX(key, time.Now().UnixNano(), period).Result()
+	if err != nil {
+		log.Printf("Error in redis CheckAndSet: %v", err)
+		return false
+	}
+	return !wasSet
+}
+
+// SetPeriod configures the TTL applied to keys written after this call.
+// NewDeduplicator calls it so the backend can enforce expiry natively.
+func (c *redisCache) SetPeriod(period time.Duration) {
+	c.period = period
+}
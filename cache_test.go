@@ -0,0 +1,148 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheGetSetDelete(t *testing.T) {
+	c := newMemoryCache()
+
+	if _, found := c.Get("a"); found {
+		t.Fatalf("Get on empty cache: got found=true, want false")
+	}
+
+	now := time.Now()
+	c.Set("a", now)
+	if got, found := c.Get("a"); !found || !got.Equal(now) {
+		t.Fatalf("Get(%q) = %v, %v; want %v, true", "a", got, found, now)
+	}
+	if got := c.Len(); got != 1 {
+		t.Fatalf("Len() = %d, want 1", got)
+	}
+
+	c.Delete("a")
+	if _, found := c.Get("a"); found {
+		t.Fatalf("Get after Delete: got found=true, want false")
+	}
+}
+
+func TestMemoryCacheExpire(t *testing.T) {
+	c := newMemoryCache()
+	c.Set("stale", time.Now().Add(-time.Hour))
+	c.Set("fresh", time.Now())
+
+	c.expire(time.Minute)
+
+	if _, found := c.Get("stale"); found {
+		t.Fatalf("expire left a stale entry in place")
+	}
+	if _, found := c.Get("fresh"); !found {
+		t.Fatalf("expire dropped a fresh entry")
+	}
+}
+
+func TestMemoryCacheCheckAndSet(t *testing.T) {
+	c := newMemoryCache()
+
+	if dup := c.CheckAndSet("k", 0); dup {
+		t.Fatalf("first CheckAndSet reported a duplicate")
+	}
+	if dup := c.CheckAndSet("k", 0); !dup {
+		t.Fatalf("second CheckAndSet for the same key did not report a duplicate")
+	}
+}
+
+func TestMemoryCacheCheckAndSetExpires(t *testing.T) {
+	c := newMemoryCache()
+	c.Set("k", time.Now().Add(-time.Hour))
+
+	if dup := c.CheckAndSet("k", time.Minute); dup {
+		t.Fatalf("CheckAndSet reported a duplicate for a key older than the period")
+	}
+	if dup := c.CheckAndSet("k", time.Minute); !dup {
+		t.Fatalf("CheckAndSet did not record the refreshed key as seen")
+	}
+}
+
+// TestMemoryCacheCheckAndSetConcurrent reproduces the concurrent
+// check-then-act race a reviewer found in isDuplicate/Check/Confirm: N
+// goroutines racing CheckAndSet for the same key must let exactly one of
+// them through as "not a duplicate".
+func TestMemoryCacheCheckAndSetConcurrent(t *testing.T) {
+	c := newMemoryCache()
+
+	const n = 64
+	var wg sync.WaitGroup
+	var notDuplicate int32
+	var mu sync.Mutex
+
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			if !c.CheckAndSet("same-key", 0) {
+				mu.Lock()
+				notDuplicate++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if notDuplicate != 1 {
+		t.Fatalf("got %d concurrent callers let through as not-duplicate, want exactly 1", notDuplicate)
+	}
+}
+
+func TestPersistentCacheReplaysAcrossRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	pc, err := newPersistentCache(dir, 0)
+	if err != nil {
+		t.Fatalf("newPersistentCache: %v", err)
+	}
+	pc.Set("a", time.Unix(1000, 0))
+	if dup := pc.CheckAndSet("b", 0); dup {
+		t.Fatalf("CheckAndSet on a fresh key reported a duplicate")
+	}
+
+	pc2, err := newPersistentCache(dir, 0)
+	if err != nil {
+		t.Fatalf("newPersistentCache (restart): %v", err)
+	}
+
+	if _, found := pc2.Get("a"); !found {
+		t.Fatalf("Set before restart was not replayed")
+	}
+	if dup := pc2.CheckAndSet("b", 0); !dup {
+		t.Fatalf("CheckAndSet before restart was not replayed")
+	}
+}
+
+func TestPersistentCacheCompact(t *testing.T) {
+	dir := t.TempDir()
+
+	pc, err := newPersistentCache(dir, 0)
+	if err != nil {
+		t.Fatalf("newPersistentCache: %v", err)
+	}
+	pc.Set("a", time.Unix(1000, 0))
+
+	if err := pc.compact(); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	if _, err := os.Stat(dir + "/" + snapshotFileName); err != nil {
+		t.Fatalf("compact did not write a snapshot: %v", err)
+	}
+
+	pc2, err := newPersistentCache(dir, 0)
+	if err != nil {
+		t.Fatalf("newPersistentCache (after compact): %v", err)
+	}
+	if _, found := pc2.Get("a"); !found {
+		t.Fatalf("compacted state was not replayed")
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"time"
+)
+
+// Token is returned by Check for a key that hasn't been confirmed seen yet.
+// Pass it to Confirm once the message has been fully processed downstream,
+// or to Rollback to discard the tentative record so a retry of the same
+// message isn't treated as a duplicate.
+type Token struct {
+	key    string
+	seenAt time.Time
+}
+
+// Check reports whether key has already been seen. If it hasn't, it
+// returns a Token to later pass to Confirm or Rollback; the key is not
+// recorded as seen until Confirm is called. This split exists so a message
+// isn't marked seen until it has actually been handled successfully,
+// mirroring the acknowledgment pattern used by messaging systems: if a
+// downstream write fails, Rollback lets a retry of the same message through
+// instead of silently dropping it as a duplicate.
+//
+// In probabilistic mode, Check commits immediately: bloom filters don't
+// support deletion, so there's no tentative state to roll back. Confirm and
+// Rollback are no-ops for tokens returned in that mode.
+//
+// Check and Confirm are two separate critical sections, not one atomic
+// operation: a key isn't recorded until Confirm runs, by design, so a
+// pending message can be rolled back. That means concurrent Check calls for
+// the same key can both observe "not seen" before either Confirms. This is
+// fine for ProcessMessagesWithConfirm's single-goroutine scan loop, but
+// callers that need a same-key check-and-record to be atomic across
+// concurrent goroutines should use isDuplicate (or cache.CheckAndSet
+// directly) instead, the way the HTTP/gRPC server and worker-pool pipeline
+// do.
+func (d *Deduplicator) Check(key string) (seen bool, token Token) {
+	if d.filter != nil {
+		return d.filter.Seen(key), Token{key: key}
+	}
+
+	if d.period == 0 {
+		_, found := d.cache.Get(key)
+		return found, Token{key: key, seenAt: time.Time{}}
+	}
+
+	lastSeen, found := d.cache.Get(key)
+	now := time.Now()
+	if found && now.Sub(lastSeen) < d.period {
+		return true, Token{key: key, seenAt: now}
+	}
+	return false, Token{key: key, seenAt: now}
+}
+
+// Confirm commits the key tracked by token into the cache, marking it seen.
+// Call it once the message returned by the corresponding Check has been
+// fully processed downstream.
+func (d *Deduplicator) Confirm(token Token) {
+	if d.cache == nil {
+		return
+	}
+	d.cache.Set(token.key, token.seenAt)
+}
+
+// Rollback discards the tentative record for token without marking the key
+// as seen. It is a no-op: Check doesn't write to the cache until Confirm,
+// so there's nothing to undo. It exists so callers have an explicit,
+// symmetric way to signal "this attempt failed, let a retry through" -
+// see ProcessMessagesWithConfirm.
+func (d *Deduplicator) Rollback(token Token) {}
+
+// ProcessMessagesWithConfirm reads messages from reader like ProcessMessages,
+// but only marks a key as seen once sink has processed the message
+// successfully. If sink returns an error, the tentative entry is rolled
+// back so a later retry of the same message isn't dropped as a duplicate.
+func (d *Deduplicator) ProcessMessagesWithConfirm(reader io.Reader, sink func(lineBytes []byte) error) {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		lineBytes := scanner.Bytes()
+
+		// An empty line is not a valid JSON, so we skip it.
+		if len(lineBytes) == 0 {
+			continue
+		}
+
+		key, action, err := d.deriveKey(lineBytes)
+		if err != nil {
+			log.Printf("Error extracting key fields: %v", err)
+			continue
+		}
+		switch action {
+		case keyActionDrop:
+			continue
+		case keyActionEmit:
+			if sinkErr := sink(lineBytes); sinkErr != nil {
+				log.Printf("Error processing message: %v", sinkErr)
+			}
+			continue
+		}
+
+		seen, token := d.Check(string(key))
+		if seen {
+			continue
+		}
+
+		if sinkErr := sink(lineBytes); sinkErr != nil {
+			log.Printf("Error processing message, rolling back: %v", sinkErr)
+			d.Rollback(token)
+			continue
+		}
+		d.Confirm(token)
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Printf("Error reading from STDIN: %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckConfirmMarksKeySeen(t *testing.T) {
+	d := NewDeduplicator(0, newMemoryCache())
+
+	seen, token := d.Check("a")
+	if seen {
+		t.Fatalf("first Check reported seen=true")
+	}
+
+	// Before Confirm, the key must not be recorded yet - Check/Confirm is a
+	// two-step process precisely so a pending message can be rolled back.
+	if seen, _ := d.Check("a"); seen {
+		t.Fatalf("Check before Confirm reported seen=true")
+	}
+
+	d.Confirm(token)
+
+	if seen, _ := d.Check("a"); !seen {
+		t.Fatalf("Check after Confirm reported seen=false")
+	}
+}
+
+func TestRollbackLetsRetryThrough(t *testing.T) {
+	d := NewDeduplicator(0, newMemoryCache())
+
+	_, token := d.Check("a")
+	d.Rollback(token)
+
+	if seen, _ := d.Check("a"); seen {
+		t.Fatalf("Check after Rollback reported seen=true, want false")
+	}
+}
+
+func TestProcessMessagesWithConfirmRollsBackOnSinkError(t *testing.T) {
+	d := NewDeduplicator(0, newMemoryCache())
+	input := strings.NewReader(`{"id":1}` + "\n")
+
+	calls := 0
+	sink := func(lineBytes []byte) error {
+		calls++
+		return errors.New("sink unavailable")
+	}
+	d.ProcessMessagesWithConfirm(input, sink)
+
+	if calls != 1 {
+		t.Fatalf("sink called %d times, want 1", calls)
+	}
+
+	// The sink failed, so the key should have been rolled back: a retry of
+	// the same message must be let through, not dropped as a duplicate.
+	retryInput := strings.NewReader(`{"id":1}` + "\n")
+	calls = 0
+	d.ProcessMessagesWithConfirm(retryInput, sink)
+	if calls != 1 {
+		t.Fatalf("retry after rollback: sink called %d times, want 1", calls)
+	}
+}
+
+func TestProcessMessagesWithConfirmCommitsOnSinkSuccess(t *testing.T) {
+	d := NewDeduplicator(0, newMemoryCache())
+	input := strings.NewReader(`{"id":1}` + "\n")
+
+	var out bytes.Buffer
+	sink := func(lineBytes []byte) error {
+		_, err := out.Write(append(append([]byte(nil), lineBytes...), '\n'))
+		return err
+	}
+	d.ProcessMessagesWithConfirm(input, sink)
+
+	if out.String() != `{"id":1}`+"\n" {
+		t.Fatalf("output = %q, want %q", out.String(), `{"id":1}`+"\n")
+	}
+
+	// The key was confirmed, so the identical message arriving again must
+	// be recognized as a duplicate and not reach the sink.
+	calls := 0
+	retryInput := strings.NewReader(`{"id":1}` + "\n")
+	d.ProcessMessagesWithConfirm(retryInput, func(lineBytes []byte) error {
+		calls++
+		return nil
+	})
+	if calls != 0 {
+		t.Fatalf("sink called %d times for a confirmed duplicate, want 0", calls)
+	}
+}
+
+// TestCheckProbabilisticCommitsImmediately verifies the documented special
+// case: in probabilistic mode, bloom filters can't support a tentative,
+// rollback-able record, so Check commits the key as seen right away and
+// Confirm/Rollback are no-ops.
+func TestCheckProbabilisticCommitsImmediately(t *testing.T) {
+	d := NewProbabilisticDeduplicator(0, 1000, 0.01)
+
+	seen, token := d.Check("a")
+	if seen {
+		t.Fatalf("first Check reported seen=true")
+	}
+
+	// Unlike exact mode, the key is already committed - a second Check
+	// before any Confirm must already report it as seen.
+	if seen, _ := d.Check("a"); !seen {
+		t.Fatalf("second Check before Confirm reported seen=false, want true (probabilistic mode commits immediately)")
+	}
+
+	// Confirm and Rollback are no-ops in this mode; neither should panic,
+	// and Rollback in particular must not un-commit the key.
+	d.Confirm(token)
+	d.Rollback(token)
+	if seen, _ := d.Check("a"); !seen {
+		t.Fatalf("Check after Rollback reported seen=false, want true (Rollback is a no-op in probabilistic mode)")
+	}
+}
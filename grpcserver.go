@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io"
+	"log"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/sudopower/deduplication-service/pb"
+)
+
+// grpcServer implements pb.DeduplicatorServer, generated from
+// proto/dedup.proto via `make generate`.
+type grpcServer struct {
+	pb.UnimplementedDeduplicatorServer
+	dedup *Deduplicator
+}
+
+// Deduplicate implements the streaming RPC: each incoming Message is
+// deduplicated and a Result is streamed back in the same order, so clients
+// can pipeline high-throughput streams without a round trip per message.
+func (s *grpcServer) Deduplicate(stream pb.Deduplicator_DeduplicateServer) error {
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// A line whose key can't be extracted still gets a Result (not a
+		// duplicate, as in Server.check) so Results stay aligned with Messages.
+		duplicate := false
+		key, action, err := s.dedup.deriveKey(msg.Line)
+		if err != nil {
+			log.Printf("Error extracting key fields: %v", err)
+		} else if action == keyActionDedupe {
+			duplicate = s.dedup.isDuplicate(string(key))
+		}
+
+		if err := stream.Send(&pb.Result{Duplicate: duplicate}); err != nil {
+			return err
+		}
+	}
+}
+
+// newGRPCServer registers a grpcServer backed by dedup on a fresh
+// *grpc.Server, ready to Serve on a net.Listener.
+func newGRPCServer(dedup *Deduplicator) *grpc.Server {
+	s := grpc.NewServer()
+	pb.RegisterDeduplicatorServer(s, &grpcServer{dedup: dedup})
+	return s
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "github.com/sudopower/deduplication-service/pb"
+)
+
+// dialGRPCServer starts dedup's grpcServer on an in-memory bufconn listener
+// and returns a client connected to it, so the streaming RPC can be
+// exercised without binding a real port.
+func dialGRPCServer(t *testing.T, dedup *Deduplicator) pb.DeduplicatorClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	t.Cleanup(func() { lis.Close() })
+
+	srv := newGRPCServer(dedup)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dialing bufconn: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewDeduplicatorClient(conn)
+}
+
+func TestGRPCDeduplicateStream(t *testing.T) {
+	client := dialGRPCServer(t, NewDeduplicator(0, newMemoryCache()))
+
+	stream, err := client.Deduplicate(context.Background())
+	if err != nil {
+		t.Fatalf("opening stream: %v", err)
+	}
+
+	lines := []string{`{"id":1}`, `{"id":2}`, `{"id":1}`}
+	want := []bool{false, false, true}
+
+	for i, line := range lines {
+		if err := stream.Send(&pb.Message{Line: []byte(line)}); err != nil {
+			t.Fatalf("Send(%d): %v", i, err)
+		}
+		result, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv(%d): %v", i, err)
+		}
+		if result.Duplicate != want[i] {
+			t.Fatalf("result[%d].Duplicate = %v, want %v", i, result.Duplicate, want[i])
+		}
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("CloseSend: %v", err)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Fatalf("Recv after CloseSend = %v, want io.EOF", err)
+	}
+}
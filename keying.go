@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// missingFieldPolicy controls what keyExtractor does when a configured key
+// field is absent from a message (or the message isn't valid JSON).
+type missingFieldPolicy int
+
+const (
+	// missingFieldSkip drops the message: it is neither deduplicated nor
+	// written to output.
+	missingFieldSkip missingFieldPolicy = iota
+	// missingFieldEmit writes the message straight to output, bypassing
+	// deduplication entirely.
+	missingFieldEmit
+	// missingFieldError drops the message and surfaces an error so the
+	// caller can log it.
+	missingFieldError
+)
+
+// parseMissingFieldPolicy parses the -missing-field-policy flag value.
+func parseMissingFieldPolicy(s string) (missingFieldPolicy, error) {
+	switch s {
+	case "skip":
+		return missingFieldSkip, nil
+	case "emit":
+		return missingFieldEmit, nil
+	case "error":
+		return missingFieldError, nil
+	default:
+		return 0, fmt.Errorf("unknown missing-field policy %q (want skip, emit, or error)", s)
+	}
+}
+
+// keyAction tells ProcessMessages what to do with a message after
+// keyExtractor.Key attempted to derive a dedup key for it.
+type keyAction int
+
+const (
+	// keyActionDedupe means a key was extracted; proceed with the normal
+	// hash-and-check flow.
+	keyActionDedupe keyAction = iota
+	// keyActionEmit means the message should be written to output
+	// unconditionally, without deduplication.
+	keyActionEmit
+	// keyActionDrop means the message should be discarded.
+	keyActionDrop
+)
+
+// keyExtractor derives a dedup key from specific JSON fields of a message,
+// rather than hashing the entire line. Fields are dotted paths (e.g.
+// "user.email") that are walked through nested JSON objects.
+type keyExtractor struct {
+	fields  []string
+	missing missingFieldPolicy
+}
+
+// newKeyExtractor builds a keyExtractor from a comma-separated list of
+// dotted field paths.
+func newKeyExtractor(fieldsCSV string, missing missingFieldPolicy) *keyExtractor {
+	rawFields := strings.Split(fieldsCSV, ",")
+	fields := make([]string, 0, len(rawFields))
+	for _, f := range rawFields {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return &keyExtractor{fields: fields, missing: missing}
+}
+
+// Key extracts and canonicalizes the configured fields from line, joining
+// them into a single key string to feed to the hasher. If a field is
+// missing, or line isn't valid JSON, the configured missingFieldPolicy
+// determines the returned action.
+func (ke *keyExtractor) Key(line []byte) (key string, action keyAction, err error) {
+	var doc map[string]interface{}
+	if jsonErr := json.Unmarshal(line, &doc); jsonErr != nil {
+		return ke.handleMissing(fmt.Errorf("parsing JSON: %w", jsonErr))
+	}
+
+	parts := make([]string, 0, len(ke.fields))
+	for _, field := range ke.fields {
+		val, found := lookupPath(doc, field)
+		if !found {
+			return ke.handleMissing(fmt.Errorf("missing key field %q", field))
+		}
+		parts = append(parts, field+"="+canonicalize(val))
+	}
+
+	return strings.Join(parts, "\x1f"), keyActionDedupe, nil
+}
+
+func (ke *keyExtractor) handleMissing(cause error) (string, keyAction, error) {
+	switch ke.missing {
+	case missingFieldEmit:
+		return "", keyActionEmit, nil
+	case missingFieldError:
+		return "", keyActionDrop, cause
+	default: // missingFieldSkip
+		return "", keyActionDrop, nil
+	}
+}
+
+// lookupPath walks a dotted path (e.g. "user.email") through nested JSON
+// objects decoded as map[string]interface{}, returning the value at that
+// path and whether it was found.
+func lookupPath(doc map[string]interface{}, path string) (interface{}, bool) {
+	var cur interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		cur, ok = m[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// canonicalize renders val (a decoded JSON value) back to a deterministic
+// JSON form, so that e.g. object field order or float formatting quirks
+// don't cause the same logical value to hash differently across messages.
+func canonicalize(val interface{}) string {
+	b, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprintf("%v", val)
+	}
+	return string(b)
+}
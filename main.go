@@ -5,11 +5,10 @@ import (
 	"crypto/sha256"
 	"flag"
 	"fmt"
-	"hash"
 	"io"
 	"log"
 	"os"
-	"sync"
+	"runtime"
 	"time"
 )
 
@@ -21,27 +20,39 @@ type Deduplicator struct {
 	// A zero value means permanent deduplication.
 	period time.Duration
 
-	// mu protects the seen map from concurrent access.
-	mu sync.RWMutex
+	// cache stores the last time a key was observed. It is backed by an
+	// in-process map by default, but can be swapped for a Redis or
+	// Memcached backend so multiple instances can share dedup state.
+	// Set only in exact mode; nil in probabilistic mode.
+	cache Cache
 
-	// seen stores the last time a key was observed.
-	// The key is of type interface{} to handle various types (numbers, strings).
-	seen map[interface{}]time.Time
+	// filter tracks seen keys probabilistically using a rotating pair of
+	// Bloom filters. Set only in probabilistic mode; nil in exact mode.
+	filter *rotatingFilter
 
-	hasher hash.Hash
+	// keyExtractor, if set, derives the dedup key from specific JSON fields
+	// of each message instead of hashing the whole line.
+	keyExtractor *keyExtractor
 }
 
-// NewDeduplicator creates and initializes a new Deduplicator instance.
-// It also starts a background cleanup goroutine if a deduplication period is specified.
-func NewDeduplicator(period time.Duration) *Deduplicator {
+// NewDeduplicator creates and initializes a new Deduplicator in exact mode,
+// backed by cache. It also starts a background cleanup goroutine if a
+// deduplication period is specified; each sweep only does work when cache
+// supports active expiry (see the expirer interface).
+func NewDeduplicator(period time.Duration, cache Cache) *Deduplicator {
+	if setter, ok := cache.(interface{ SetPeriod(time.Duration) }); ok {
+		setter.SetPeriod(period)
+	}
+
 	d := &Deduplicator{
 		period: period,
-		seen:   make(map[interface{}]time.Time),
-		hasher: sha256.New(),
+		cache:  cache,
 	}
 
 	// If a period is set, we need to periodically clean up old entries
-	// from the 'seen' map to prevent memory from growing indefinitely.
+	// from the cache to prevent memory from growing indefinitely. Backends
+	// with native TTL support are not affected, since cleanupExpired is a
+	// no-op for them.
 	if period > 0 {
 		go d.startCleanup()
 	}
@@ -49,6 +60,24 @@ func NewDeduplicator(period time.Duration) *Deduplicator {
 	return d
 }
 
+// NewProbabilisticDeduplicator creates a Deduplicator in probabilistic mode,
+// suitable for streams with far more keys than can affordably be tracked
+// with a time.Time per key. capacity and fpr size the underlying rotating
+// Bloom filter pair; see rotatingFilter for the rotation/expiry semantics.
+func NewProbabilisticDeduplicator(period time.Duration, capacity int, fpr float64) *Deduplicator {
+	return &Deduplicator{
+		period: period,
+		filter: newRotatingFilter(capacity, fpr, period),
+	}
+}
+
+// WithKeyExtractor configures d to derive its dedup key from specific JSON
+// fields instead of hashing the whole line. It returns d for chaining.
+func (d *Deduplicator) WithKeyExtractor(ke *keyExtractor) *Deduplicator {
+	d.keyExtractor = ke
+	return d
+}
+
 // ProcessMessages reads messages from the provided reader, deduplicates them,
 // and writes the unique messages to the writer.
 func (d *Deduplicator) ProcessMessages(writer io.Writer, reader io.Reader) {
@@ -61,10 +90,19 @@ func (d *Deduplicator) ProcessMessages(writer io.Writer, reader io.Reader) {
 			continue
 		}
 
-		// Check if the key is a duplicate.
-		d.hasher.Reset()
-		d.hasher.Write(lineBytes)
-		key := d.hasher.Sum(nil)
+		key, action, err := d.deriveKey(lineBytes)
+		if err != nil {
+			log.Printf("Error extracting key fields: %v", err)
+			continue
+		}
+		switch action {
+		case keyActionDrop:
+			continue
+		case keyActionEmit:
+			fmt.Fprintln(writer, string(lineBytes))
+			continue
+		}
+
 		if !d.isDuplicate(string(key)) {
 			// If not a duplicate, write the original message to the output.
 			fmt.Fprintln(writer, string(lineBytes))
@@ -77,37 +115,46 @@ func (d *Deduplicator) ProcessMessages(writer io.Writer, reader io.Reader) {
 	}
 }
 
-// isDuplicate checks if a key has been seen before within the deduplication period.
-// It returns true if the message is a duplicate, and false otherwise.
-// If the message is not a duplicate, it records the key and the current time.
-func (d *Deduplicator) isDuplicate(key interface{}) bool {
-	// Permanent deduplication (period is 0)
-	if d.period == 0 {
-		d.mu.Lock()
-		defer d.mu.Unlock()
-
-		if _, found := d.seen[key]; found {
-			return true // Found, so it's a duplicate.
+// deriveKey computes the hash key to use for lineBytes, honoring any
+// configured keyExtractor. action reports whether the caller should dedupe
+// normally, emit the line unconditionally, or drop it; key is only
+// meaningful when action is keyActionDedupe.
+//
+// Each call hashes independently via sha256.Sum256 rather than through a
+// shared hash.Hash, so deriveKey is safe to call concurrently from
+// multiple goroutines (see ProcessMessagesParallel and the HTTP/gRPC
+// server handlers).
+func (d *Deduplicator) deriveKey(lineBytes []byte) (key []byte, action keyAction, err error) {
+	keyInput := lineBytes
+	if d.keyExtractor != nil {
+		extracted, a, extractErr := d.keyExtractor.Key(lineBytes)
+		if extractErr != nil {
+			return nil, keyActionDrop, extractErr
+		}
+		if a != keyActionDedupe {
+			return nil, a, nil
 		}
-		d.seen[key] = time.Time{} // Store it permanently. The time value doesn't matter.
-		return false
+		keyInput = []byte(extracted)
 	}
 
-	// Timed deduplication
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	lastSeen, found := d.seen[key]
-	now := time.Now()
+	sum := sha256.Sum256(keyInput)
+	return sum[:], keyActionDedupe, nil
+}
 
-	// If found and the time since last seen is less than the period, it's a duplicate.
-	if found && now.Sub(lastSeen) < d.period {
-		return true
+// isDuplicate checks if a key has been seen before within the deduplication
+// period. It returns true if the message is a duplicate, and false
+// otherwise. If the message is not a duplicate, it records the key and the
+// current time.
+//
+// The check and the record happen as a single atomic operation - filter.Seen
+// in probabilistic mode, cache.CheckAndSet in exact mode - so concurrent
+// calls for the same key (from the HTTP/gRPC server or the worker-pool
+// pipeline) can't all observe "not a duplicate".
+func (d *Deduplicator) isDuplicate(key string) bool {
+	if d.filter != nil {
+		return d.filter.Seen(key)
 	}
-
-	// Otherwise, it's not a duplicate. Record the time we saw it.
-	d.seen[key] = now
-	return false
+	return d.cache.CheckAndSet(key, d.period)
 }
 
 // startCleanup runs a periodic task to remove expired keys from the 'seen' map.
@@ -124,22 +171,40 @@ func (d *Deduplicator) startCleanup() {
 	}
 }
 
-// cleanupExpired iterates over the map and removes keys that are older than the period.
-func (d *Deduplicator) cleanupExpired() {
-	d.mu.Lock()
-	defer d.mu.Unlock()
+// FilterStats returns the current load factor and estimated false-positive
+// rate of the probabilistic filter, and ok=false in exact mode.
+func (d *Deduplicator) FilterStats() (stats FilterStats, ok bool) {
+	if d.filter == nil {
+		return FilterStats{}, false
+	}
+	return d.filter.Stats(), true
+}
 
-	now := time.Now()
-	for key, lastSeen := range d.seen {
-		if now.Sub(lastSeen) > d.period {
-			delete(d.seen, key)
-		}
+// cleanupExpired asks the cache backend to drop keys older than the period.
+// It is a no-op for backends with native TTL support (Redis, Memcached),
+// since those don't implement expirer.
+func (d *Deduplicator) cleanupExpired() {
+	if ex, ok := d.cache.(expirer); ok {
+		ex.expire(d.period)
 	}
 }
 
 func main() {
 	// Define and parse command-line flags
 	dedupPeriod := flag.Duration("period", 0, "Optional: Deduplication period (e.g., '10s', '5m', '1h'). If not set, deduplication is permanent.")
+	cacheKind := flag.String("cache", "memory", "Cache backend to use: memory, redis, or memcached (exact mode only).")
+	cacheURL := flag.String("cache-url", "", "Connection URL for the redis/memcached cache backend (ignored for memory).")
+	mode := flag.String("mode", "exact", "Deduplication mode: exact or probabilistic.")
+	capacity := flag.Int("capacity", 10_000_000, "Probabilistic mode: expected number of keys per filter rotation.")
+	fpr := flag.Float64("fpr", 0.01, "Probabilistic mode: target false-positive rate.")
+	keyFields := flag.String("key-fields", "", "Optional: comma-separated JSON field paths (e.g. 'id,user.email') to key on instead of hashing the whole line.")
+	missingFieldPolicyFlag := flag.String("missing-field-policy", "skip", "When a -key-fields field is missing: skip, emit, or error.")
+	stateDir := flag.String("state-dir", "", "Optional: directory to persist dedup state (WAL + snapshots) across restarts, for the memory cache backend.")
+	serveAddr := flag.String("serve", "", "Optional: HTTP address to serve on (e.g. ':8080') instead of reading from stdin.")
+	grpcAddr := flag.String("grpc-serve", "", "Optional: gRPC address to serve on (e.g. ':9090'), alongside -serve.")
+	workers := flag.Int("workers", runtime.GOMAXPROCS(0), "Number of worker goroutines hashing and deduplicating lines in parallel.")
+	preserveOrder := flag.Bool("preserve-order", true, "Preserve input order in the output; disable for max throughput.")
+	confirmWrites := flag.Bool("confirm-writes", false, "Use the Check/Confirm/Rollback API instead of committing a key as seen immediately: a key is only marked seen once its line has been written to stdout, so a write failure lets a retry of the same message through instead of dropping it as a duplicate. Processes stdin sequentially; incompatible with -serve/-grpc-serve.")
 
 	flag.Parse()
 
@@ -149,11 +214,53 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Create and run the service
-	log.Printf("Starting deduplication service with period '%v'", *dedupPeriod)
+	var deduplicator *Deduplicator
+	switch *mode {
+	case "exact":
+		cache, err := newCache(*cacheKind, *cacheURL, *stateDir, *dedupPeriod)
+		if err != nil {
+			log.Printf("Error: %v", err)
+			os.Exit(1)
+		}
+		log.Printf("Starting deduplication service with period '%v' (mode=exact, cache=%s, state-dir=%q)", *dedupPeriod, *cacheKind, *stateDir)
+		deduplicator = NewDeduplicator(*dedupPeriod, cache)
+	case "probabilistic":
+		log.Printf("Starting deduplication service with period '%v' (mode=probabilistic, capacity=%d, fpr=%v)", *dedupPeriod, *capacity, *fpr)
+		deduplicator = NewProbabilisticDeduplicator(*dedupPeriod, *capacity, *fpr)
+	default:
+		log.Printf("Error: unknown -mode %q (want exact or probabilistic)", *mode)
+		os.Exit(1)
+	}
+
+	if *keyFields != "" {
+		policy, err := parseMissingFieldPolicy(*missingFieldPolicyFlag)
+		if err != nil {
+			log.Printf("Error: %v", err)
+			os.Exit(1)
+		}
+		deduplicator.WithKeyExtractor(newKeyExtractor(*keyFields, policy))
+	}
+
+	if *confirmWrites && (*serveAddr != "" || *grpcAddr != "") {
+		log.Println("Error: -confirm-writes cannot be combined with -serve/-grpc-serve.")
+		os.Exit(1)
+	}
+
+	if *serveAddr != "" || *grpcAddr != "" {
+		serve(deduplicator, *serveAddr, *grpcAddr)
+		return
+	}
+
+	if *confirmWrites {
+		deduplicator.ProcessMessagesWithConfirm(os.Stdin, func(lineBytes []byte) error {
+			_, err := fmt.Fprintln(os.Stdout, string(lineBytes))
+			return err
+		})
+		log.Println("Deduplication service finished.")
+		return
+	}
 
-	deduplicator := NewDeduplicator(*dedupPeriod)
-	deduplicator.ProcessMessages(os.Stdout, os.Stdin)
+	deduplicator.ProcessMessagesParallel(os.Stdout, os.Stdin, *workers, *preserveOrder)
 
 	log.Println("Deduplication service finished.")
 }
@@ -0,0 +1,49 @@
+// Code generated by hand as a stand-in for protoc-gen-go output: protoc and
+// protoc-gen-go are not available in this build environment. Regenerate with
+// `make generate` once that tooling is available; until then, this file
+// defines the same wire-compatible message types from proto/dedup.proto by
+// hand, using the struct-tag convention that google.golang.org/protobuf's
+// legacy-message support understands.
+
+package pb
+
+import (
+	proto "github.com/golang/protobuf/proto"
+)
+
+// Message is a single line to be deduplicated.
+type Message struct {
+	Line []byte `protobuf:"bytes,1,opt,name=line,proto3" json:"line,omitempty"`
+}
+
+func (m *Message) Reset()         { *m = Message{} }
+func (m *Message) String() string { return proto.CompactTextString(m) }
+func (*Message) ProtoMessage()    {}
+
+func (m *Message) GetLine() []byte {
+	if m != nil {
+		return m.Line
+	}
+	return nil
+}
+
+// Result reports whether the corresponding Message was a duplicate.
+type Result struct {
+	Duplicate bool `protobuf:"varint,1,opt,name=duplicate,proto3" json:"duplicate,omitempty"`
+}
+
+func (m *Result) Reset()         { *m = Result{} }
+func (m *Result) String() string { return proto.CompactTextString(m) }
+func (*Result) ProtoMessage()    {}
+
+func (m *Result) GetDuplicate() bool {
+	if m != nil {
+		return m.Duplicate
+	}
+	return false
+}
+
+func init() {
+	proto.RegisterType((*Message)(nil), "dedup.Message")
+	proto.RegisterType((*Result)(nil), "dedup.Result")
+}
@@ -0,0 +1,121 @@
+// Code generated by hand as a stand-in for protoc-gen-go-grpc output: see
+// dedup.pb.go. Regenerate with `make generate` once protoc tooling is
+// available in the build environment.
+
+package pb
+
+import (
+	context "context"
+
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// DeduplicatorClient is the client API for the Deduplicator service.
+type DeduplicatorClient interface {
+	Deduplicate(ctx context.Context, opts ...grpc.CallOption) (Deduplicator_DeduplicateClient, error)
+}
+
+type deduplicatorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewDeduplicatorClient creates a client for the Deduplicator service over cc.
+func NewDeduplicatorClient(cc grpc.ClientConnInterface) DeduplicatorClient {
+	return &deduplicatorClient{cc}
+}
+
+func (c *deduplicatorClient) Deduplicate(ctx context.Context, opts ...grpc.CallOption) (Deduplicator_DeduplicateClient, error) {
+	stream, err := c.cc.NewStream(ctx, &deduplicatorServiceDesc.Streams[0], "/dedup.Deduplicator/Deduplicate", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &deduplicatorDeduplicateClient{stream}, nil
+}
+
+// Deduplicator_DeduplicateClient is the client-side stream for the
+// Deduplicate RPC.
+type Deduplicator_DeduplicateClient interface {
+	Send(*Message) error
+	Recv() (*Result, error)
+	grpc.ClientStream
+}
+
+type deduplicatorDeduplicateClient struct {
+	grpc.ClientStream
+}
+
+func (x *deduplicatorDeduplicateClient) Send(m *Message) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *deduplicatorDeduplicateClient) Recv() (*Result, error) {
+	m := new(Result)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DeduplicatorServer is the server API for the Deduplicator service.
+type DeduplicatorServer interface {
+	Deduplicate(Deduplicator_DeduplicateServer) error
+}
+
+// UnimplementedDeduplicatorServer can be embedded in a DeduplicatorServer
+// implementation for forward compatibility with new RPCs added later.
+type UnimplementedDeduplicatorServer struct{}
+
+func (UnimplementedDeduplicatorServer) Deduplicate(Deduplicator_DeduplicateServer) error {
+	return status.Errorf(codes.Unimplemented, "method Deduplicate not implemented")
+}
+
+// RegisterDeduplicatorServer registers srv as the handler for the
+// Deduplicator service on s.
+func RegisterDeduplicatorServer(s *grpc.Server, srv DeduplicatorServer) {
+	s.RegisterService(&deduplicatorServiceDesc, srv)
+}
+
+func deduplicatorDeduplicateHandler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DeduplicatorServer).Deduplicate(&deduplicatorDeduplicateServer{stream})
+}
+
+// Deduplicator_DeduplicateServer is the server-side stream for the
+// Deduplicate RPC.
+type Deduplicator_DeduplicateServer interface {
+	Send(*Result) error
+	Recv() (*Message, error)
+	grpc.ServerStream
+}
+
+type deduplicatorDeduplicateServer struct {
+	grpc.ServerStream
+}
+
+func (x *deduplicatorDeduplicateServer) Send(m *Result) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *deduplicatorDeduplicateServer) Recv() (*Message, error) {
+	m := new(Message)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+var deduplicatorServiceDesc = grpc.ServiceDesc{
+	ServiceName: "dedup.Deduplicator",
+	HandlerType: (*DeduplicatorServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Deduplicate",
+			Handler:       deduplicatorDeduplicateHandler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "proto/dedup.proto",
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	walFileName      = "dedup.wal"
+	snapshotFileName = "dedup.snapshot"
+)
+
+// persistentCache wraps memoryCache with on-disk durability: every Set is
+// appended to a write-ahead log, a background goroutine periodically
+// compacts the WAL into a full snapshot of the live entries, and
+// newPersistentCache replays the latest snapshot plus WAL tail on startup
+// to rehydrate state after a restart.
+type persistentCache struct {
+	*memoryCache
+
+	dir     string
+	walMu   sync.Mutex
+	walFile *os.File
+}
+
+// newPersistentCache opens (or creates) the WAL and snapshot files under
+// dir, replays any existing state into memory, and starts a background
+// goroutine that compacts the WAL on the given interval.
+func newPersistentCache(dir string, compactionInterval time.Duration) (*persistentCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating state dir: %w", err)
+	}
+
+	mc := newMemoryCache()
+	if err := replayState(dir, mc); err != nil {
+		return nil, fmt.Errorf("replaying persisted state: %w", err)
+	}
+
+	walFile, err := os.OpenFile(filepath.Join(dir, walFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening WAL: %w", err)
+	}
+
+	c := &persistentCache{memoryCache: mc, dir: dir, walFile: walFile}
+
+	if compactionInterval <= 0 {
+		compactionInterval = time.Minute
+	}
+	go c.startCompaction(compactionInterval)
+
+	return c, nil
+}
+
+// Set records key in memory, like memoryCache.Set, and appends the same
+// record to the WAL so it survives a restart.
+func (c *persistentCache) Set(key string, t time.Time) {
+	c.memoryCache.Set(key, t)
+
+	c.walMu.Lock()
+	defer c.walMu.Unlock()
+	if err := writeRecord(c.walFile, key, t); err != nil {
+		log.Printf("Error appending to WAL: %v", err)
+	}
+}
+
+// CheckAndSet checks and records key in memory under the same critical
+// section as memoryCache.CheckAndSet, then appends the record to the WAL
+// while still holding it, so a concurrent compact never snapshots and
+// truncates in between and leaves a key recorded in memory but in neither
+// the snapshot nor the WAL.
+func (c *persistentCache) CheckAndSet(key string, period time.Duration) bool {
+	now := time.Now()
+
+	c.memoryCache.mu.Lock()
+	defer c.memoryCache.mu.Unlock()
+	if c.memoryCache.checkAndSetLocked(key, period, now) {
+		return true
+	}
+
+	c.walMu.Lock()
+	defer c.walMu.Unlock()
+	if err := writeRecord(c.walFile, key, now); err != nil {
+		log.Printf("Error appending to WAL: %v", err)
+	}
+	return false
+}
+
+// startCompaction runs compact on a ticker, similar to
+// Deduplicator.startCleanup, so the WAL doesn't grow unbounded between
+// restarts.
+func (c *persistentCache) startCompaction(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := c.compact(); err != nil {
+			log.Printf("Error compacting dedup state: %v", err)
+		}
+	}
+}
+
+// compact writes a full snapshot of the currently live entries and
+// truncates the WAL, so a future replay only has to read the WAL tail
+// written since the last compaction.
+func (c *persistentCache) compact() error {
+	// Lock order matches CheckAndSet (memoryCache.mu, then walMu) so the two
+	// can't deadlock.
+	c.memoryCache.mu.RLock()
+	defer c.memoryCache.mu.RUnlock()
+	c.walMu.Lock()
+	defer c.walMu.Unlock()
+
+	snapshotPath := filepath.Join(c.dir, snapshotFileName)
+	tmpPath := snapshotPath + ".tmp"
+
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("creating snapshot tmp file: %w", err)
+	}
+
+	for key, t := range c.memoryCache.seen {
+		if writeErr := writeRecord(tmp, key, t); writeErr != nil {
+			tmp.Close()
+			return fmt.Errorf("writing snapshot: %w", writeErr)
+		}
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing snapshot tmp file: %w", err)
+	}
+	if err := os.Rename(tmpPath, snapshotPath); err != nil {
+		return fmt.Errorf("installing snapshot: %w", err)
+	}
+
+	if err := c.walFile.Truncate(0); err != nil {
+		return fmt.Errorf("truncating WAL: %w", err)
+	}
+	_, err = c.walFile.Seek(0, io.SeekStart)
+	return err
+}
+
+// replayState rehydrates mc from the latest snapshot, if any, followed by
+// the WAL tail written since that snapshot.
+func replayState(dir string, mc *memoryCache) error {
+	if err := replayFile(filepath.Join(dir, snapshotFileName), mc); err != nil {
+		return fmt.Errorf("replaying snapshot: %w", err)
+	}
+	if err := replayFile(filepath.Join(dir, walFileName), mc); err != nil {
+		return fmt.Errorf("replaying WAL: %w", err)
+	}
+	return nil
+}
+
+func replayFile(path string, mc *memoryCache) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		key, t, err := readRecord(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		mc.Set(key, t)
+	}
+}
+
+// writeRecord appends one WAL/snapshot record: a 4-byte big-endian key
+// length, an 8-byte big-endian unix-nano timestamp, then the key bytes.
+func writeRecord(w io.Writer, key string, t time.Time) error {
+	keyBytes := []byte(key)
+
+	header := make([]byte, 12)
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(keyBytes)))
+	binary.BigEndian.PutUint64(header[4:12], uint64(t.UnixNano()))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(keyBytes)
+	return err
+}
+
+func readRecord(r *bufio.Reader) (key string, t time.Time, err error) {
+	header := make([]byte, 12)
+	if _, err = io.ReadFull(r, header); err != nil {
+		return "", time.Time{}, err
+	}
+
+	keyLen := binary.BigEndian.Uint32(header[0:4])
+	nanos := int64(binary.BigEndian.Uint64(header[4:12]))
+
+	keyBytes := make([]byte, keyLen)
+	if _, err = io.ReadFull(r, keyBytes); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return string(keyBytes), time.Unix(0, nanos), nil
+}
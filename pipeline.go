@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"runtime"
+	"sync"
+)
+
+// pipelineResult is the outcome of deduplicating one input line, tagged
+// with its original position so the writer can restore input order when
+// preserveOrder is enabled.
+type pipelineResult struct {
+	index     int
+	lineBytes []byte
+	duplicate bool
+	dropped   bool
+}
+
+// ProcessMessagesParallel is a worker-pool variant of ProcessMessages: a
+// pool of workers hashes and deduplicates lines concurrently, while a
+// single writer goroutine emits the non-duplicates. With preserveOrder,
+// output order matches input order, at the cost of buffering results that
+// complete out of turn; without it, messages are emitted as soon as their
+// worker finishes, for maximum throughput on CPU-bound hashing workloads.
+//
+// workers <= 0 defaults to runtime.GOMAXPROCS(0).
+func (d *Deduplicator) ProcessMessagesParallel(writer io.Writer, reader io.Reader, workers int, preserveOrder bool) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	type job struct {
+		index     int
+		lineBytes []byte
+	}
+
+	jobs := make(chan job, workers*2)
+	results := make(chan pipelineResult, workers*2)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results <- d.processLine(j.index, j.lineBytes)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	go func() {
+		defer close(jobs)
+		scanner := bufio.NewScanner(reader)
+		index := 0
+		for scanner.Scan() {
+			lineBytes := scanner.Bytes()
+			// An empty line is not a valid JSON, so we skip it.
+			if len(lineBytes) == 0 {
+				continue
+			}
+			// The scanner reuses its buffer on the next Scan, so each job
+			// needs its own copy.
+			line := append([]byte(nil), lineBytes...)
+			jobs <- job{index: index, lineBytes: line}
+			index++
+		}
+		if err := scanner.Err(); err != nil {
+			log.Printf("Error reading from STDIN: %v", err)
+		}
+	}()
+
+	if !preserveOrder {
+		for r := range results {
+			if !r.dropped && !r.duplicate {
+				fmt.Fprintln(writer, string(r.lineBytes))
+			}
+		}
+		return
+	}
+
+	// Results complete out of order; buffer them until the next expected
+	// index is available so output order matches input order.
+	pending := make(map[int]pipelineResult)
+	next := 0
+	for r := range results {
+		pending[r.index] = r
+		for {
+			res, ok := pending[next]
+			if !ok {
+				break
+			}
+			if !res.dropped && !res.duplicate {
+				fmt.Fprintln(writer, string(res.lineBytes))
+			}
+			delete(pending, next)
+			next++
+		}
+	}
+}
+
+// processLine derives the dedup key for lineBytes and checks it. It touches
+// no per-Deduplicator mutable state other than the thread-safe cache/filter,
+// so it's safe to call from multiple worker goroutines at once.
+func (d *Deduplicator) processLine(index int, lineBytes []byte) pipelineResult {
+	key, action, err := d.deriveKey(lineBytes)
+	if err != nil {
+		log.Printf("Error extracting key fields: %v", err)
+		return pipelineResult{index: index, dropped: true}
+	}
+
+	switch action {
+	case keyActionDrop:
+		return pipelineResult{index: index, dropped: true}
+	case keyActionEmit:
+		return pipelineResult{index: index, lineBytes: lineBytes}
+	}
+
+	return pipelineResult{index: index, lineBytes: lineBytes, duplicate: d.isDuplicate(string(key))}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"testing"
+)
+
+// benchLines returns n newline-delimited, mutually unique JSON lines, so
+// every line is new work for the worker pool to hash and no line is
+// short-circuited as a duplicate.
+func benchLines(n int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, `{"id":%d}`+"\n", i)
+	}
+	return buf.Bytes()
+}
+
+// BenchmarkProcessMessagesParallel measures throughput at increasing worker
+// counts for the same CPU-bound hashing workload, to demonstrate the
+// near-linear scaling on multi-core hosts that motivated the worker pool.
+// Run with -cpu=1,2,4,8 to compare across GOMAXPROCS as well.
+func BenchmarkProcessMessagesParallel(b *testing.B) {
+	input := benchLines(50_000)
+
+	for _, workers := range []int{1, 2, 4, 8, runtime.GOMAXPROCS(0)} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.SetBytes(int64(len(input)))
+			for i := 0; i < b.N; i++ {
+				d := NewDeduplicator(0, newMemoryCache())
+				d.ProcessMessagesParallel(io.Discard, bytes.NewReader(input), workers, true)
+			}
+		})
+	}
+}
+
+// BenchmarkProcessMessagesParallelUnordered compares the preserve-order and
+// unordered output paths at a fixed worker count.
+func BenchmarkProcessMessagesParallelUnordered(b *testing.B) {
+	input := benchLines(50_000)
+	workers := runtime.GOMAXPROCS(0)
+
+	for _, preserveOrder := range []bool{true, false} {
+		b.Run(fmt.Sprintf("preserveOrder=%v", preserveOrder), func(b *testing.B) {
+			b.SetBytes(int64(len(input)))
+			for i := 0; i < b.N; i++ {
+				d := NewDeduplicator(0, newMemoryCache())
+				d.ProcessMessagesParallel(io.Discard, bytes.NewReader(input), workers, preserveOrder)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// FilterStats reports the current load and estimated false-positive rate of
+// a rotatingFilter's active bloomFilter, exposed over the status endpoint.
+type FilterStats struct {
+	LoadFactor   float64
+	EstimatedFPR float64
+}
+
+// rotatingFilter implements probabilistic deduplication using a pair of
+// bloom filters that rotate on a half-period cadence: inserts always land in
+// the newer (active) filter, lookups check both, and at each half-period
+// tick the older filter is dropped and replaced with a fresh one. This
+// bounds memory at the cost of a small, known false-positive rate, for
+// streams with far too many keys to hold a time.Time per key.
+//
+// A zero period disables rotation: the active filter is never replaced,
+// matching the permanent-deduplication behavior of the exact cache path.
+type rotatingFilter struct {
+	mu       sync.RWMutex
+	active   *bloomFilter
+	previous *bloomFilter
+	capacity int
+	fpr      float64
+}
+
+// newRotatingFilter creates a rotatingFilter sized for capacity items at the
+// target false-positive rate fpr, and starts the rotation goroutine if
+// period is non-zero.
+func newRotatingFilter(capacity int, fpr float64, period time.Duration) *rotatingFilter {
+	rf := &rotatingFilter{
+		active:   newBloomFilter(capacity, fpr),
+		previous: newBloomFilter(capacity, fpr),
+		capacity: capacity,
+		fpr:      fpr,
+	}
+
+	if period > 0 {
+		go rf.startRotation(period / 2)
+	}
+
+	return rf
+}
+
+// Seen reports whether key has already been observed in either the active
+// or previous filter. If not, it records key in the active filter and
+// returns false.
+func (rf *rotatingFilter) Seen(key string) bool {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.active.Test(key) || rf.previous.Test(key) {
+		return true
+	}
+	rf.active.Add(key)
+	return false
+}
+
+// startRotation drops the older filter and allocates a fresh one every
+// halfPeriod, giving each filter a full period of coverage across its time
+// as the active and then the previous filter.
+func (rf *rotatingFilter) startRotation(halfPeriod time.Duration) {
+	ticker := time.NewTicker(halfPeriod)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		rf.rotate()
+	}
+}
+
+func (rf *rotatingFilter) rotate() {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	rf.previous = rf.active
+	rf.active = newBloomFilter(rf.capacity, rf.fpr)
+}
+
+// Stats reports the current load factor and estimated false-positive rate
+// of the active filter.
+func (rf *rotatingFilter) Stats() FilterStats {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+
+	return FilterStats{
+		LoadFactor:   rf.active.LoadFactor(),
+		EstimatedFPR: rf.active.EstimatedFPR(),
+	}
+}
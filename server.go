@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+)
+
+// Server exposes deduplication as a network service so other processes can
+// call /check or /bulk instead of piping lines through stdin, with /stats
+// for observability. See grpcserver.go for the equivalent streaming gRPC
+// interface.
+type Server struct {
+	dedup *Deduplicator
+}
+
+// NewServer creates a Server backed by dedup.
+func NewServer(dedup *Deduplicator) *Server {
+	return &Server{dedup: dedup}
+}
+
+type checkRequest struct {
+	Message string `json:"message"`
+}
+
+type checkResponse struct {
+	Duplicate bool `json:"duplicate"`
+}
+
+// handleCheck implements POST /check: the request body names the message
+// to check, and the response reports whether it's a duplicate.
+func (s *Server) handleCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req checkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "decoding request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, checkResponse{Duplicate: s.check([]byte(req.Message))})
+}
+
+// handleBulk implements POST /bulk: the request body is newline-delimited
+// messages, and the response is one JSON object per line reporting whether
+// each message was a duplicate, in the same order.
+func (s *Server) handleBulk(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	enc := json.NewEncoder(w)
+	for scanner.Scan() {
+		lineBytes := scanner.Bytes()
+		if len(lineBytes) == 0 {
+			continue
+		}
+		if err := enc.Encode(checkResponse{Duplicate: s.check(lineBytes)}); err != nil {
+			log.Printf("Error encoding bulk response: %v", err)
+			return
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Printf("Error reading bulk request body: %v", err)
+	}
+}
+
+// check derives the dedup key for lineBytes and reports whether it's a
+// duplicate, treating a dropped key (per the missing-field policy) as "not
+// a duplicate" since there's nothing to compare it against.
+func (s *Server) check(lineBytes []byte) bool {
+	key, action, err := s.dedup.deriveKey(lineBytes)
+	if err != nil {
+		log.Printf("Error extracting key fields: %v", err)
+		return false
+	}
+	if action != keyActionDedupe {
+		return false
+	}
+	return s.dedup.isDuplicate(string(key))
+}
+
+type statsResponse struct {
+	Mode         string  `json:"mode"`
+	Size         int     `json:"size,omitempty"`
+	LoadFactor   float64 `json:"load_factor,omitempty"`
+	EstimatedFPR float64 `json:"estimated_fpr,omitempty"`
+}
+
+// handleStats implements GET /stats, reporting cache size in exact mode or
+// Bloom filter load/FPR in probabilistic mode.
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if stats, ok := s.dedup.FilterStats(); ok {
+		writeJSON(w, statsResponse{Mode: "probabilistic", LoadFactor: stats.LoadFactor, EstimatedFPR: stats.EstimatedFPR})
+		return
+	}
+	writeJSON(w, statsResponse{Mode: "exact", Size: s.dedup.cache.Len()})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// ListenAndServe registers the HTTP handlers and blocks serving on addr.
+func (s *Server) ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/check", s.handleCheck)
+	mux.HandleFunc("/bulk", s.handleBulk)
+	mux.HandleFunc("/stats", s.handleStats)
+
+	log.Printf("Serving deduplication HTTP API on %s", addr)
+	return http.ListenAndServe(addr, mux)
+}
+
+// serve runs dedup as a long-lived network service instead of processing
+// stdin: httpAddr starts the HTTP API (see Server), grpcAddr starts the
+// streaming gRPC API (see grpcserver.go). Either may be empty to run just
+// the other; it blocks until the running server(s) exit.
+func serve(dedup *Deduplicator, httpAddr, grpcAddr string) {
+	if grpcAddr != "" {
+		lis, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			log.Fatalf("Error: listening on %s: %v", grpcAddr, err)
+		}
+		go func() {
+			log.Printf("Serving deduplication gRPC API on %s", grpcAddr)
+			if err := newGRPCServer(dedup).Serve(lis); err != nil {
+				log.Fatalf("Error: gRPC server: %v", err)
+			}
+		}()
+	}
+
+	if httpAddr != "" {
+		if err := NewServer(dedup).ListenAndServe(httpAddr); err != nil {
+			log.Fatalf("Error: HTTP server: %v", err)
+		}
+		return
+	}
+
+	// gRPC-only: block forever so the goroutine above keeps serving.
+	select {}
+}
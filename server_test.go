@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleCheck(t *testing.T) {
+	s := NewServer(NewDeduplicator(0, newMemoryCache()))
+
+	body := `{"message":"hello"}`
+	req := httptest.NewRequest(http.MethodPost, "/check", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	s.handleCheck(rec, req)
+
+	var first checkResponse
+	if err := json.NewDecoder(rec.Body).Decode(&first); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if first.Duplicate {
+		t.Fatalf("first /check of a new message reported Duplicate=true")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/check", strings.NewReader(body))
+	rec = httptest.NewRecorder()
+	s.handleCheck(rec, req)
+
+	var second checkResponse
+	if err := json.NewDecoder(rec.Body).Decode(&second); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if !second.Duplicate {
+		t.Fatalf("second /check of the same message reported Duplicate=false")
+	}
+}
+
+func TestHandleCheckRejectsWrongMethod(t *testing.T) {
+	s := NewServer(NewDeduplicator(0, newMemoryCache()))
+
+	req := httptest.NewRequest(http.MethodGet, "/check", nil)
+	rec := httptest.NewRecorder()
+	s.handleCheck(rec, req)
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestHandleBulk(t *testing.T) {
+	s := NewServer(NewDeduplicator(0, newMemoryCache()))
+
+	body := "{\"id\":1}\n{\"id\":2}\n{\"id\":1}\n"
+	req := httptest.NewRequest(http.MethodPost, "/bulk", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	s.handleBulk(rec, req)
+
+	dec := json.NewDecoder(rec.Body)
+	var got []checkResponse
+	for dec.More() {
+		var r checkResponse
+		if err := dec.Decode(&r); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+		got = append(got, r)
+	}
+
+	want := []checkResponse{{Duplicate: false}, {Duplicate: false}, {Duplicate: true}}
+	if len(got) != len(want) {
+		t.Fatalf("got %d responses, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("response[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestHandleStatsExactMode(t *testing.T) {
+	dedup := NewDeduplicator(0, newMemoryCache())
+	s := NewServer(dedup)
+
+	req := httptest.NewRequest(http.MethodGet, "/stats", nil)
+	rec := httptest.NewRecorder()
+	s.handleStats(rec, req)
+
+	var stats statsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if stats.Mode != "exact" {
+		t.Fatalf("Mode = %q, want %q", stats.Mode, "exact")
+	}
+
+	s.check([]byte(`{"id":1}`))
+	rec = httptest.NewRecorder()
+	s.handleStats(rec, req)
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if stats.Size != 1 {
+		t.Fatalf("Size = %d, want 1", stats.Size)
+	}
+}
+
+func TestHandleStatsProbabilisticMode(t *testing.T) {
+	dedup := NewProbabilisticDeduplicator(0, 1000, 0.01)
+	s := NewServer(dedup)
+
+	req := httptest.NewRequest(http.MethodGet, "/stats", nil)
+	rec := httptest.NewRecorder()
+	s.handleStats(rec, req)
+
+	var stats statsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if stats.Mode != "probabilistic" {
+		t.Fatalf("Mode = %q, want %q", stats.Mode, "probabilistic")
+	}
+}